@@ -0,0 +1,269 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"json-viewer/pathexpr"
+)
+
+// Formatter renders a decoded, post-processed KVMap as a single output
+// record. Selected via -format.
+type Formatter interface {
+	Format(m KVMap, w io.Writer) error
+}
+
+// flusher is implemented by formatters that buffer records and need a
+// final flush once the input is exhausted (e.g. TableFormatter).
+type flusher interface {
+	Flush(w io.Writer) error
+}
+
+// newFormatter builds the Formatter named by -format, falling back to
+// KVFormatter for unknown names.
+func newFormatter(name string) Formatter {
+	switch name {
+	case "jsonl":
+		return JSONLFormatter{}
+	case "tsv":
+		return NewTSVFormatter()
+	case "table":
+		return NewTableFormatter()
+	default:
+		return KVFormatter{}
+	}
+}
+
+// KVFormatter renders ANSI-colored `key=value` lines, one per field. This
+// is the original, default rendering (KVMap.String()).
+type KVFormatter struct{}
+
+func (KVFormatter) Format(m KVMap, w io.Writer) error {
+	_, err := fmt.Fprintf(w, "%s\n", m)
+	return err
+}
+
+// JSONLFormatter re-emits each record as a single line of canonical JSON,
+// honoring -skip/-only/-order for which fields are included and in what
+// order.
+type JSONLFormatter struct{}
+
+func (JSONLFormatter) Format(m KVMap, w io.Writer) error {
+	keys := m.filteredKeys()
+
+	var sb strings.Builder
+	sb.WriteByte('{')
+	for i, key := range keys {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		kb, err := json.Marshal(key)
+		if err != nil {
+			return err
+		}
+		vb, err := json.Marshal(pruneValue(key, m[key]))
+		if err != nil {
+			return err
+		}
+		sb.Write(kb)
+		sb.WriteByte(':')
+		sb.Write(vb)
+	}
+	sb.WriteByte('}')
+
+	_, err := fmt.Fprintln(w, sb.String())
+	return err
+}
+
+// pruneValue recursively applies -skip/-only to a decoded value, dropping
+// nested object fields whose full dotted path (path + "." + key) is
+// excluded. Used by JSONLFormatter so nested -only/-skip prune the same
+// fields the KV formatter hides, instead of only filtering top-level keys.
+func pruneValue(path string, val interface{}) interface{} {
+	switch v := val.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for key, sub := range v {
+			if sub == "" || sub == nil {
+				continue
+			}
+			full := path + "." + key
+			if skip(full) {
+				continue
+			}
+			if len(showOnlyKeyList) > 0 && !showKey(full) {
+				continue
+			}
+			out[key] = pruneValue(full, sub)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, elem := range v {
+			out[i] = pruneValue(fmt.Sprintf("%s[%d]", path, i), elem)
+		}
+		return out
+	default:
+		return val
+	}
+}
+
+// TSVFormatter renders tab-separated rows with a header row. The header is
+// fixed to the -only list when given; otherwise it tracks the union of
+// keys seen so far, reprinting the header line whenever that set changes.
+type TSVFormatter struct {
+	fixedHeader []string
+	lastHeader  []string
+}
+
+func NewTSVFormatter() *TSVFormatter {
+	f := &TSVFormatter{}
+	if showOnlyKeys != "" {
+		f.fixedHeader = strings.Split(showOnlyKeys, ",")
+	}
+	return f
+}
+
+func (f *TSVFormatter) Format(m KVMap, w io.Writer) error {
+	keys := f.fixedHeader
+	if len(keys) == 0 {
+		keys = m.filteredKeys()
+	}
+
+	if !stringsEqual(f.lastHeader, keys) {
+		if _, err := fmt.Fprintln(w, strings.Join(keys, "\t")); err != nil {
+			return err
+		}
+		f.lastHeader = keys
+	}
+
+	vals := make([]string, len(keys))
+	for i, key := range keys {
+		if val, ok := columnValue(m, key); ok {
+			vals[i] = fmt.Sprintf("%v", val)
+		}
+	}
+	_, err := fmt.Fprintln(w, strings.Join(vals, "\t"))
+	return err
+}
+
+// tableWindowSize is the number of rows TableFormatter buffers before
+// flushing, so that column widths can be computed from a full chunk of
+// rows rather than widening forever as new keys appear.
+const tableWindowSize = 20
+
+// TableFormatter renders records as an aligned, space-padded table,
+// buffering rows in chunks of tableWindowSize so columns can realign when
+// new keys show up.
+type TableFormatter struct {
+	rows []KVMap
+}
+
+func NewTableFormatter() *TableFormatter {
+	return &TableFormatter{}
+}
+
+func (f *TableFormatter) Format(m KVMap, w io.Writer) error {
+	f.rows = append(f.rows, m)
+	if len(f.rows) >= tableWindowSize {
+		return f.Flush(w)
+	}
+	return nil
+}
+
+func (f *TableFormatter) Flush(w io.Writer) error {
+	if len(f.rows) == 0 {
+		return nil
+	}
+
+	cols := f.columns()
+	widths := make(map[string]int, len(cols))
+	for _, col := range cols {
+		widths[col] = len(col)
+	}
+	for _, row := range f.rows {
+		for _, col := range cols {
+			val, _ := columnValue(row, col)
+			if s := fmt.Sprintf("%v", val); len(s) > widths[col] {
+				widths[col] = len(s)
+			}
+		}
+	}
+
+	header := make([]string, len(cols))
+	for i, col := range cols {
+		header[i] = padRight(col, widths[col])
+	}
+	if _, err := fmt.Fprintln(w, strings.Join(header, "  ")); err != nil {
+		return err
+	}
+
+	for _, row := range f.rows {
+		cells := make([]string, len(cols))
+		for i, col := range cols {
+			s := ""
+			if val, ok := columnValue(row, col); ok {
+				s = fmt.Sprintf("%v", val)
+			}
+			cells[i] = padRight(s, widths[col])
+		}
+		if _, err := fmt.Fprintln(w, strings.Join(cells, "  ")); err != nil {
+			return err
+		}
+	}
+
+	f.rows = nil
+	return nil
+}
+
+// columns picks the table's column set: the -only list when given,
+// otherwise the union of keys seen across the buffered window, ordered by
+// -order/keyOrder.
+func (f *TableFormatter) columns() []string {
+	if showOnlyKeys != "" {
+		return strings.Split(showOnlyKeys, ",")
+	}
+
+	seen := make(map[string]bool)
+	var cols []string
+	for _, row := range f.rows {
+		for _, key := range row.filteredKeys() {
+			if !seen[key] {
+				seen[key] = true
+				cols = append(cols, key)
+			}
+		}
+	}
+	return orderSortKeys(cols, keyOrder)
+}
+
+func padRight(s string, width int) string {
+	if len(s) >= width {
+		return s
+	}
+	return s + strings.Repeat(" ", width-len(s))
+}
+
+// columnValue resolves a (possibly dotted) -only column name against m,
+// walking into nested objects/arrays via pathexpr so that columns like
+// "req.path" read the nested field rather than a literal top-level key.
+func columnValue(m KVMap, col string) (interface{}, bool) {
+	p, err := pathexpr.Parse(col)
+	if err != nil {
+		return nil, false
+	}
+	return p.Get(map[string]interface{}(m))
+}
+
+func stringsEqual(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -15,7 +15,11 @@ import (
 
 	"github.com/fatih/color"
 	jsoniter "github.com/json-iterator/go"
+	"github.com/mattn/go-isatty"
 	"github.com/pkg/errors"
+
+	"json-viewer/pathexpr"
+	"json-viewer/sqlparse"
 )
 
 // use jsoniner for handling JSON stuff
@@ -27,16 +31,28 @@ var (
 	markNonJson bool
 	// separate json and non-json with extra newline
 	sepOK bool
-	// skip these keys in output (comma-separated)
+	// skip these keys in output (comma-separated paths, e.g. req.status)
 	skipKeys    string
-	skipKeyList []string
-	// show only these keys in output (comma-separated)
+	skipKeyList []pathexpr.Path
+	// show only these keys in output (comma-separated paths, e.g. req.path)
 	showOnlyKeys    string
-	showOnlyKeyList []string
+	showOnlyKeyList []pathexpr.Path
 	// when only is specified show entries that have all fields present
 	showOnlyGroup bool
 	// key order (comma-separated), overrides keyOrder
 	orderKeys string
+	// filter records by predicate, e.g. 'level==error && req.status>500'
+	whereExpr string
+	whereAST  pathexpr.WhereExpr
+	// output format: kv|jsonl|tsv|table
+	format    string
+	formatter Formatter
+	// flatten nested objects into dotted key.subkey=value lines instead of
+	// rendering them inline as key={subkey=value ...}
+	flatten bool
+	// Go time layout used to render time.Time values (e.g. from
+	// convertUnixTimestamp); empty uses time.Time's default String()
+	timeFormat string
 	// skip post-processing
 	skipPostProc bool
 	// colorize all keys
@@ -45,6 +61,11 @@ var (
 	colorizeKeys string
 	// enable re-scan (will exit after first scanner.Scan exits)
 	rescan bool
+	// comma-separated list of files to tail instead of reading stdin
+	followFiles string
+	// max number of bulk INSERT rows to render inline in sql_fields_map
+	// (0 = unlimited)
+	bulkRows int
 )
 
 var (
@@ -138,18 +159,30 @@ func levelColor(lvl string) func(a ...interface{}) string {
 	}
 }
 
+// skip reports whether key (a plain key or, for nested fields, a dotted
+// path such as "req.path") is excluded by -skip.
 func skip(key string) bool {
-	for _, k := range skipKeyList {
-		if k == key {
+	kp, err := pathexpr.Parse(key)
+	if err != nil {
+		return false
+	}
+	for _, p := range skipKeyList {
+		if p.IsPrefixOf(kp) {
 			return true
 		}
 	}
 	return false
 }
 
+// showKey reports whether key (a plain key or, for nested fields, a dotted
+// path such as "req.path") is included by -only.
 func showKey(key string) bool {
-	for _, k := range showOnlyKeyList {
-		if k == key {
+	kp, err := pathexpr.Parse(key)
+	if err != nil {
+		return false
+	}
+	for _, p := range showOnlyKeyList {
+		if p.Overlaps(kp) {
 			return true
 		}
 	}
@@ -160,21 +193,20 @@ func showKey(key string) bool {
 // serialized into
 type KVMap map[string]interface{}
 
-func (kvMap KVMap) String() string {
+// filteredKeys returns the map's keys in display order, with empty/nil
+// values and keys excluded by -skip/-only already removed. Shared by all
+// Formatter implementations so they agree on which fields to render.
+func (kvMap KVMap) filteredKeys() []string {
 	var keys []string
 	for key := range kvMap {
 		keys = append(keys, key)
 	}
 	keys = orderSortKeys(keys, keyOrder)
-	var output []string
 
-	keyCol := keyColor(kvMap)
+	var out []string
 	for _, key := range keys {
 		val := kvMap[key]
-		if val == "" {
-			continue
-		}
-		if val == nil {
+		if val == "" || val == nil {
 			continue
 		}
 		if skip(key) {
@@ -183,6 +215,17 @@ func (kvMap KVMap) String() string {
 		if len(showOnlyKeyList) > 0 && !showKey(key) {
 			continue
 		}
+		out = append(out, key)
+	}
+	return out
+}
+
+func (kvMap KVMap) String() string {
+	var output []string
+
+	keyCol := keyColor(kvMap)
+	for _, key := range kvMap.filteredKeys() {
+		val := kvMap[key]
 		if key == "level" {
 			switch val {
 			case "info":
@@ -195,24 +238,17 @@ func (kvMap KVMap) String() string {
 			continue
 		}
 
-		// override value color?
-		if col, ok := colorizeKeyVals[key]; ok {
-			valColor := color.New(col).SprintFunc()
-			output = append(output, fmt.Sprintf("%s=%v\n", keyCol(key), valColor(kvMap[key])))
-		} else {
-			output = append(output, fmt.Sprintf("%s=%v\n", keyCol(key), kvMap[key]))
-		}
+		output = append(output, renderNested(key, key, val, keyCol)...)
 	}
 	return strings.Join(output, "")
 }
 
-func (kvMap KVMap) HasKeys(keys []string) bool {
-	if len(keys) == 0 {
+func (kvMap KVMap) HasKeys(paths []pathexpr.Path) bool {
+	if len(paths) == 0 {
 		return true
 	}
-	for _, key := range keys {
-		_, ok := kvMap[key]
-		if !ok {
+	for _, p := range paths {
+		if _, ok := p.Get(map[string]interface{}(kvMap)); !ok {
 			return false
 		}
 	}
@@ -231,22 +267,32 @@ func json2kvmap(input []byte, into *KVMap) error {
 func init() {
 	flag.BoolVar(&markNonJson, "mark", false, "mark non-json input?")
 	flag.BoolVar(&sepOK, "sep", false, "separate JSON and non-JSON")
-	flag.StringVar(&skipKeys, "skip", "", "comma-separated list of keys to be skipped from output")
-	flag.StringVar(&showOnlyKeys, "only", "", "comma-separated list of keys to be shown only and the rest skipped from output")
+	flag.StringVar(&skipKeys, "skip", "", "comma-separated list of key paths to be skipped from output (e.g. req.status)")
+	flag.StringVar(&showOnlyKeys, "only", "", "comma-separated list of key paths to be shown only and the rest skipped from output (e.g. req.path)")
 	flag.BoolVar(&showOnlyGroup, "group", false, "group entries that have all fields present when using -only")
 	flag.StringVar(&orderKeys, "order", "", "comma-separated list of keys order")
+	flag.StringVar(&whereExpr, "where", "", "filter records by predicate, e.g. 'level==error && req.status>500'")
 	flag.BoolVar(&skipPostProc, "no-pp", false, "skip post-processing")
 	flag.BoolVar(&colorizeAllKeys, "colorize", false, "colorize all keys")
 	flag.StringVar(&colorizeKeys, "colorize-keys", "", "comma-separated list of additional keys to colorize")
 	flag.BoolVar(&rescan, "rescan", false, "enable Scanner restart")
+	flag.StringVar(&followFiles, "follow", "", "comma-separated list of files to tail instead of reading stdin, with truncation/rotation detection")
+	flag.IntVar(&bulkRows, "bulk-rows", 0, "max number of bulk INSERT rows to render inline in sql_fields_map, 0 for unlimited")
+	flag.StringVar(&format, "format", "kv", "output format: kv|jsonl|tsv|table")
+	flag.BoolVar(&flatten, "flatten", false, "flatten nested objects into dotted key.subkey=value lines instead of key={subkey=value ...}")
+	flag.StringVar(&timeFormat, "time-format", "", "Go time layout used to render timestamp fields (default: time.Time's default format)")
 	flag.Parse()
 
+	if !isatty.IsTerminal(os.Stdout.Fd()) && !isatty.IsCygwinTerminal(os.Stdout.Fd()) {
+		color.NoColor = true
+	}
+
 	if skipKeys != "" {
-		skipKeyList = strings.Split(skipKeys, ",")
+		skipKeyList = mustParsePaths("skip", skipKeys)
 	}
 
 	if showOnlyKeys != "" {
-		showOnlyKeyList = strings.Split(showOnlyKeys, ",")
+		showOnlyKeyList = mustParsePaths("only", showOnlyKeys)
 	}
 
 	if orderKeys != "" {
@@ -258,49 +304,44 @@ func init() {
 			colorizeKeyVals[key] = color.FgHiYellow
 		}
 	}
+
+	if whereExpr != "" {
+		ast, err := pathexpr.ParseWhere(whereExpr)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid -where expression: %v\n", err)
+			os.Exit(2)
+		}
+		whereAST = ast
+	}
+
+	formatter = newFormatter(format)
+}
+
+// mustParsePaths parses a comma-separated list of pathexpr paths passed to
+// flagName, exiting with an error message on the first invalid entry.
+func mustParsePaths(flagName, raw string) []pathexpr.Path {
+	var paths []pathexpr.Path
+	for _, part := range strings.Split(raw, ",") {
+		p, err := pathexpr.Parse(part)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid -%s path %q: %v\n", flagName, part, err)
+			os.Exit(2)
+		}
+		paths = append(paths, p)
+	}
+	return paths
 }
 
 func main() {
+	if followFiles != "" {
+		runFollow(strings.Split(followFiles, ","))
+		return
+	}
+
 	for {
 		scanner := bufio.NewScanner(os.Stdin)
 		for scanner.Scan() {
-			line := scanner.Bytes()
-			if len(line) == 0 {
-				continue
-			}
-			// does line start with file name (tailing multiple files)
-			if reFileName.Match(line) {
-				line = reFileName.ReplaceAll(line, jsonStartReplace)
-			}
-			// if not, does it start with jsonStart
-			if !bytes.HasPrefix(line, jsonStart) {
-				sep := "\n"
-				if sepOK {
-					sep = "\n\n"
-				}
-				if markNonJson {
-					fmt.Printf("%s\n%v%s", yellow("[not json]"), string(line), sep)
-				} else {
-					fmt.Printf("%s%s", string(line), sep)
-				}
-				continue
-			}
-			kvMap := make(KVMap)
-			if err := json2kvmap(line, &kvMap); err != nil {
-				continue
-			}
-			if !skipPostProc {
-				postprocess(kvMap)
-			}
-
-			if showOnlyGroup && !kvMap.HasKeys(showOnlyKeyList) {
-				continue
-			}
-
-			// everything OK here - print keyval
-			if len(kvMap) > 0 {
-				fmt.Printf("%s\n", kvMap)
-			}
+			processLine("", scanner.Bytes())
 		}
 
 		// continue scanning after pause (file truncation)
@@ -310,11 +351,82 @@ func main() {
 			break
 		}
 	}
+
+	flushFormatter()
+}
+
+// runFollow tails the given files via tailFiles and feeds every line
+// through processLine until the follower exits (it never does, short of a
+// read error on every followed file).
+func runFollow(paths []string) {
+	for fl := range tailFiles(paths) {
+		processLine(fl.tag, fl.data)
+	}
+	flushFormatter()
+}
+
+func flushFormatter() {
+	if f, ok := formatter.(flusher); ok {
+		if err := f.Flush(os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "format error: %v\n", err)
+		}
+	}
+}
+
+// processLine decodes and renders a single input line. tag, if non-empty,
+// is a short label (e.g. a filename) prefixed to the output so records from
+// multiple followed files can be told apart.
+func processLine(tag string, line []byte) {
+	if len(line) == 0 {
+		return
+	}
+	// does line start with file name (tailing multiple files)
+	if reFileName.Match(line) {
+		line = reFileName.ReplaceAll(line, jsonStartReplace)
+	}
+	// if not, does it start with jsonStart
+	if !bytes.HasPrefix(line, jsonStart) {
+		sep := "\n"
+		if sepOK {
+			sep = "\n\n"
+		}
+		if markNonJson {
+			fmt.Printf("%s\n%v%s", yellow("[not json]"), string(line), sep)
+		} else {
+			fmt.Printf("%s%s", string(line), sep)
+		}
+		return
+	}
+	kvMap := make(KVMap)
+	if err := json2kvmap(line, &kvMap); err != nil {
+		return
+	}
+	if !skipPostProc {
+		postprocess(kvMap)
+	}
+
+	if whereAST != nil && !whereAST.Eval(map[string]interface{}(kvMap)) {
+		return
+	}
+
+	if showOnlyGroup && !kvMap.HasKeys(showOnlyKeyList) {
+		return
+	}
+
+	// everything OK here - print keyval
+	if len(kvMap) > 0 {
+		if tag != "" {
+			fmt.Printf("%s ", cyan("["+tag+"]"))
+		}
+		if err := formatter.Format(kvMap, os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "format error: %v\n", err)
+		}
+	}
 }
 
 func postprocess(m KVMap) {
 	mergeQueryAndParams(m)
-	addInsertParamMap(m)
+	addFieldsMap(m)
 	mergeFileAndFunc(m)
 	convertUnixTimestamp(m)
 }
@@ -391,10 +503,11 @@ func mergeFileAndFunc(m KVMap) {
 	delete(m, "file")
 }
 
-// addInsertParamMap adds `sql_insert_map` to log which consists of columns and
-// values found in SQL INSERT query key `sql`.
+// addFieldsMap adds `sql_fields_map` to the log, a colored key=value summary
+// of the column/value bindings found in the SQL query key `sql`, regardless
+// of whether it's an INSERT, UPDATE, DELETE or SELECT statement.
 // Run this *after* mergeQueryAndParams() function.
-func addInsertParamMap(m KVMap) {
+func addFieldsMap(m KVMap) {
 	sqlVal, ok := m["sql"]
 	if !ok {
 		return
@@ -405,113 +518,65 @@ func addInsertParamMap(m KVMap) {
 		return
 	}
 
-	parsed, err := parseInsert(sqlQuery)
+	stmt, err := sqlparse.Analyze(sqlQuery)
 	if err != nil {
 		return
 	}
 
-	if len(parsed.Values) != len(parsed.Columns) {
+	var pairs []string
+	switch s := stmt.(type) {
+	case *sqlparse.InsertStmt:
+		for _, row := range s.Rows {
+			if len(row) != len(s.Columns) {
+				return
+			}
+		}
+		if len(s.Rows) == 1 {
+			for i, col := range s.Columns {
+				pairs = append(pairs, fmt.Sprintf("%s=%s", green(col), s.Rows[0][i]))
+			}
+			break
+		}
+		limit := len(s.Rows)
+		if bulkRows > 0 && bulkRows < limit {
+			limit = bulkRows
+		}
+		for _, row := range s.Rows[:limit] {
+			var rowPairs []string
+			for i, col := range s.Columns {
+				rowPairs = append(rowPairs, fmt.Sprintf("%s=%s", green(col), row[i]))
+			}
+			pairs = append(pairs, "["+strings.Join(rowPairs, " ")+"]")
+		}
+		if limit < len(s.Rows) {
+			pairs = append(pairs, fmt.Sprintf("...(+%d more)", len(s.Rows)-limit))
+		}
+	case *sqlparse.UpdateStmt:
+		for _, a := range s.Set {
+			pairs = append(pairs, fmt.Sprintf("%s=%s", green(a.Column), a.Value))
+		}
+		pairs = append(pairs, predicatePairs(s.Where)...)
+	case *sqlparse.DeleteStmt:
+		pairs = append(pairs, predicatePairs(s.Where)...)
+	case *sqlparse.SelectStmt:
+		pairs = append(pairs, predicatePairs(s.Where)...)
+	default:
 		return
 	}
 
-	var params []string
-	for i := 0; i < len(parsed.Values); i++ {
-		key := parsed.Columns[i]
-		val := parsed.Values[i]
-		params = append(params, fmt.Sprintf("%s=%s", green(key), val))
-	}
-
-	m["sql_insert_map"] = strings.Join(params, " ")
-}
-
-// SQL insert query consists of:
-// INSERT INTO "table" ("c1", "c2", ..., "cn")
-// VALUES ('v1', v2, ..., NULL, ..., true, ...)
-// RETURNING "table"."col"
-// which will be matched into:
-//
-//	1: table name
-//	2: columns
-//	3: values
-//	4: return table
-//	5: return column
-var reSqlInsert = regexp.MustCompile(
-	`INSERT INTO "([^\"]+)" \(([^\)]+)\) VALUES \(([^\)]+)\) RETURNING "([^\"]+)"."([^\"]+)"`)
-
-var reComma = regexp.MustCompile(`,`)
-
-// total number of significant matches
-var expectMatches = 6
-
-var ErrInvalidMatchCount = errors.New("Invalid match count.")
-
-// Insert represents information about parsed SQL query.
-type Insert struct {
-	// Table name to get inserted data.
-	Table string
-	// Values to be inserted.
-	Values []string
-	// Column names.
-	Columns []string
-	// Returning Table name.
-	RetTable string
-	// RetTable column name.
-	RetColumn string
-}
-
-// parseInsert parses Insert SQL query and returns parse information.
-func parseInsert(sql string) (Insert, error) {
-	matches := reSqlInsert.FindStringSubmatch(sql)
-	if len(matches) != expectMatches {
-		return Insert{}, ErrInvalidMatchCount
-	}
-	insert := Insert{
-		Table:     matches[1],
-		Columns:   parseValues(matches[2]),
-		Values:    parseQuotedValues(matches[3]),
-		RetTable:  matches[4],
-		RetColumn: matches[5],
+	if len(pairs) == 0 {
+		return
 	}
 
-	return insert, nil
+	m["sql_fields_map"] = strings.Join(pairs, " ")
 }
 
-// parseQuotedValues splits quoted value string into list of strings.
-// Value string contains all column values in the form:
-// `'val1', 'val2', ..., 'val_n'` where a single val can be empty.
-// Example: ”,'02a56888-ea30-11eb-b3e9-1f5878e115ac','73553','false','Dolní Lutyně, 73553, Stará cesta 1014','<nil>','<nil>','<nil>'
-func parseQuotedValues(str string) []string {
-	var start = []rune("'")[0]
-	var values []string
-	var begin bool
-	var token string
-	for _, char := range str {
-		if char == start {
-			if begin {
-				values = append(values, token)
-				begin = false
-				token = ""
-				continue
-			}
-			begin = true
-			continue
-		}
-		if begin {
-			token += string(char)
-		}
+// predicatePairs renders WHERE predicates as colored `key=value` pairs,
+// dropping the comparison operator (the summary only needs the binding).
+func predicatePairs(preds []sqlparse.Predicate) []string {
+	var pairs []string
+	for _, pr := range preds {
+		pairs = append(pairs, fmt.Sprintf("%s%s%s", green(pr.Column), pr.Op, pr.Value))
 	}
-	return values
-}
-
-func parseValues(str string) []string {
-	vals := reComma.Split(str, -1)
-	res := []string{}
-	for _, val := range vals {
-		val = strings.TrimPrefix(val, `"`)
-		val = strings.TrimSuffix(val, `"`)
-		val = strings.TrimPrefix(val, `'`)
-		val = strings.TrimSuffix(val, `'`)
-		res = append(res, val)
-	}
-	return res
+	return pairs
 }
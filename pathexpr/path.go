@@ -0,0 +1,141 @@
+// Package pathexpr implements a small JSONPath/gjson-style path language
+// for addressing fields inside decoded JSON (map[string]interface{} /
+// []interface{} trees), plus a boolean expression language built on top of
+// it for the `-where` flag.
+//
+// Paths are dotted, with `[i]` for array indices, e.g. `req.path`,
+// `tags[0]`, `ctx.user.id`.
+package pathexpr
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Segment is one step of a Path: either a map key lookup or an array index.
+type Segment struct {
+	Key     string
+	Index   int
+	IsIndex bool
+}
+
+// Path is a sequence of Segments identifying a field inside a decoded JSON
+// value.
+type Path []Segment
+
+// Parse parses a dotted path expression such as `req.path` or `tags[0]`
+// into a Path.
+func Parse(s string) (Path, error) {
+	if s == "" {
+		return nil, fmt.Errorf("pathexpr: empty path")
+	}
+	var path Path
+	for _, part := range strings.Split(s, ".") {
+		if part == "" {
+			return nil, fmt.Errorf("pathexpr: empty path segment in %q", s)
+		}
+		key, indices, err := splitIndices(part)
+		if err != nil {
+			return nil, err
+		}
+		path = append(path, Segment{Key: key})
+		for _, idx := range indices {
+			path = append(path, Segment{Index: idx, IsIndex: true})
+		}
+	}
+	return path, nil
+}
+
+// splitIndices splits a single path segment such as `tags[0][1]` into its
+// key (`tags`) and index chain (`[0, 1]`).
+func splitIndices(part string) (string, []int, error) {
+	i := strings.IndexByte(part, '[')
+	if i < 0 {
+		return part, nil, nil
+	}
+	key := part[:i]
+	rest := part[i:]
+
+	var indices []int
+	for len(rest) > 0 {
+		if rest[0] != '[' {
+			return "", nil, fmt.Errorf("pathexpr: malformed index in %q", part)
+		}
+		end := strings.IndexByte(rest, ']')
+		if end < 0 {
+			return "", nil, fmt.Errorf("pathexpr: unterminated index in %q", part)
+		}
+		idx, err := strconv.Atoi(rest[1:end])
+		if err != nil {
+			return "", nil, fmt.Errorf("pathexpr: invalid index in %q: %w", part, err)
+		}
+		indices = append(indices, idx)
+		rest = rest[end+1:]
+	}
+	return key, indices, nil
+}
+
+// Get walks root (expected to be built from map[string]interface{} /
+// []interface{}, as produced by decoding JSON) following the path and
+// returns the value found there. ok is false if any segment along the way
+// doesn't exist.
+func (p Path) Get(root interface{}) (interface{}, bool) {
+	cur := root
+	for _, seg := range p {
+		if seg.IsIndex {
+			arr, ok := cur.([]interface{})
+			if !ok || seg.Index < 0 || seg.Index >= len(arr) {
+				return nil, false
+			}
+			cur = arr[seg.Index]
+			continue
+		}
+		m, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		v, ok := m[seg.Key]
+		if !ok {
+			return nil, false
+		}
+		cur = v
+	}
+	return cur, true
+}
+
+// Overlaps reports whether p and other refer to the same field or one is
+// an ancestor of the other (e.g. `req` overlaps `req.path`). It's used to
+// decide whether a -only path applies to a given field: either the field
+// is (an ancestor of) the requested path, so traversal should continue
+// into it, or it's (a descendant of) the requested path, so it should be
+// shown in full.
+func (p Path) Overlaps(other Path) bool {
+	n := len(p)
+	if len(other) < n {
+		n = len(other)
+	}
+	for i := 0; i < n; i++ {
+		if p[i] != other[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// IsPrefixOf reports whether p is other, or an ancestor of it (e.g. `req`
+// is a prefix of `req.path`). Unlike Overlaps this is directional: it's
+// used for -skip, where naming a shallow ancestor should drop its entire
+// subtree, but naming a deeper descendant should only drop that leaf and
+// must not also hide shallower siblings on the way down.
+func (p Path) IsPrefixOf(other Path) bool {
+	if len(p) > len(other) {
+		return false
+	}
+	for i := range p {
+		if p[i] != other[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,274 @@
+package pathexpr
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// WhereExpr is a parsed `-where` predicate. Eval reports whether root
+// (typically a decoded KVMap) matches it.
+type WhereExpr interface {
+	Eval(root interface{}) bool
+}
+
+type andExpr struct{ left, right WhereExpr }
+
+func (e *andExpr) Eval(root interface{}) bool { return e.left.Eval(root) && e.right.Eval(root) }
+
+type orExpr struct{ left, right WhereExpr }
+
+func (e *orExpr) Eval(root interface{}) bool { return e.left.Eval(root) || e.right.Eval(root) }
+
+// cmpExpr is a single `path OP value` comparison.
+type cmpExpr struct {
+	path  Path
+	op    string
+	value string
+	re    *regexp.Regexp // set only when op == "~"
+}
+
+func (e *cmpExpr) Eval(root interface{}) bool {
+	val, ok := e.path.Get(root)
+	switch e.op {
+	case "!=":
+		if !ok {
+			return true
+		}
+		return fmt.Sprintf("%v", val) != e.value
+	case "==":
+		if !ok {
+			return false
+		}
+		return fmt.Sprintf("%v", val) == e.value
+	case "~":
+		if !ok {
+			return false
+		}
+		return e.re.MatchString(fmt.Sprintf("%v", val))
+	case ">", "<", ">=", "<=":
+		if !ok {
+			return false
+		}
+		fv, ok1 := toFloat(val)
+		nv, ok2 := toFloat(e.value)
+		if !ok1 || !ok2 {
+			return false
+		}
+		switch e.op {
+		case ">":
+			return fv > nv
+		case "<":
+			return fv < nv
+		case ">=":
+			return fv >= nv
+		default:
+			return fv <= nv
+		}
+	default:
+		return false
+	}
+}
+
+// toFloat coerces a decoded JSON value (or a literal string) to a float64
+// for numeric comparisons.
+func toFloat(v interface{}) (float64, bool) {
+	if f, ok := v.(float64); ok {
+		return f, true
+	}
+	f, err := strconv.ParseFloat(fmt.Sprintf("%v", v), 64)
+	if err != nil {
+		return 0, false
+	}
+	return f, true
+}
+
+// ParseWhere parses a `-where` expression, e.g.
+// `level==error && req.status>=500`, into a WhereExpr. Supported operators
+// are `==`, `!=`, `~` (regexp match), `>`, `<`, `>=` and `<=`, combined
+// with `&&`, `||` and parentheses.
+func ParseWhere(expr string) (WhereExpr, error) {
+	sc := &scanner{s: expr}
+	e, err := sc.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	sc.skipSpace()
+	if sc.pos != len(sc.s) {
+		return nil, fmt.Errorf("pathexpr: unexpected trailing input %q", sc.s[sc.pos:])
+	}
+	return e, nil
+}
+
+type scanner struct {
+	s   string
+	pos int
+}
+
+func (sc *scanner) skipSpace() {
+	for sc.pos < len(sc.s) && sc.s[sc.pos] == ' ' {
+		sc.pos++
+	}
+}
+
+func (sc *scanner) rest() string {
+	return sc.s[sc.pos:]
+}
+
+func (sc *scanner) parseOr() (WhereExpr, error) {
+	left, err := sc.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		sc.skipSpace()
+		if !strings.HasPrefix(sc.rest(), "||") {
+			break
+		}
+		sc.pos += 2
+		right, err := sc.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &orExpr{left, right}
+	}
+	return left, nil
+}
+
+func (sc *scanner) parseAnd() (WhereExpr, error) {
+	left, err := sc.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		sc.skipSpace()
+		if !strings.HasPrefix(sc.rest(), "&&") {
+			break
+		}
+		sc.pos += 2
+		right, err := sc.parsePrimary()
+		if err != nil {
+			return nil, err
+		}
+		left = &andExpr{left, right}
+	}
+	return left, nil
+}
+
+func (sc *scanner) parsePrimary() (WhereExpr, error) {
+	sc.skipSpace()
+	if sc.pos < len(sc.s) && sc.s[sc.pos] == '(' {
+		sc.pos++
+		e, err := sc.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		sc.skipSpace()
+		if sc.pos >= len(sc.s) || sc.s[sc.pos] != ')' {
+			return nil, fmt.Errorf("pathexpr: expected ')' at %d", sc.pos)
+		}
+		sc.pos++
+		return e, nil
+	}
+	return sc.parseComparison()
+}
+
+func isOpChar(c byte) bool {
+	return c == '=' || c == '!' || c == '~' || c == '>' || c == '<'
+}
+
+func (sc *scanner) parseComparison() (WhereExpr, error) {
+	sc.skipSpace()
+	start := sc.pos
+	for sc.pos < len(sc.s) && sc.s[sc.pos] != ' ' && !isOpChar(sc.s[sc.pos]) {
+		sc.pos++
+	}
+	if sc.pos == start {
+		return nil, fmt.Errorf("pathexpr: expected path expression at %d", start)
+	}
+	path, err := Parse(sc.s[start:sc.pos])
+	if err != nil {
+		return nil, err
+	}
+
+	sc.skipSpace()
+	op, err := sc.readOp()
+	if err != nil {
+		return nil, err
+	}
+
+	sc.skipSpace()
+	value, err := sc.readValue()
+	if err != nil {
+		return nil, err
+	}
+
+	e := &cmpExpr{path: path, op: op, value: value}
+	if op == "~" {
+		re, err := regexp.Compile(value)
+		if err != nil {
+			return nil, fmt.Errorf("pathexpr: invalid regexp %q: %w", value, err)
+		}
+		e.re = re
+	}
+	return e, nil
+}
+
+func (sc *scanner) readOp() (string, error) {
+	switch {
+	case strings.HasPrefix(sc.rest(), "=="):
+		sc.pos += 2
+		return "==", nil
+	case strings.HasPrefix(sc.rest(), "!="):
+		sc.pos += 2
+		return "!=", nil
+	case strings.HasPrefix(sc.rest(), ">="):
+		sc.pos += 2
+		return ">=", nil
+	case strings.HasPrefix(sc.rest(), "<="):
+		sc.pos += 2
+		return "<=", nil
+	case strings.HasPrefix(sc.rest(), "~"):
+		sc.pos++
+		return "~", nil
+	case strings.HasPrefix(sc.rest(), ">"):
+		sc.pos++
+		return ">", nil
+	case strings.HasPrefix(sc.rest(), "<"):
+		sc.pos++
+		return "<", nil
+	default:
+		return "", fmt.Errorf("pathexpr: expected comparison operator at %d", sc.pos)
+	}
+}
+
+// readValue reads a comparison value: either a quoted string (single or
+// double quotes, no escaping) or a bare token terminated by whitespace, a
+// closing paren, or the next `&&`/`||`.
+func (sc *scanner) readValue() (string, error) {
+	if sc.pos < len(sc.s) && (sc.s[sc.pos] == '\'' || sc.s[sc.pos] == '"') {
+		quote := sc.s[sc.pos]
+		sc.pos++
+		start := sc.pos
+		for sc.pos < len(sc.s) && sc.s[sc.pos] != quote {
+			sc.pos++
+		}
+		if sc.pos >= len(sc.s) {
+			return "", fmt.Errorf("pathexpr: unterminated quoted value")
+		}
+		val := sc.s[start:sc.pos]
+		sc.pos++
+		return val, nil
+	}
+
+	start := sc.pos
+	for sc.pos < len(sc.s) && sc.s[sc.pos] != ' ' && sc.s[sc.pos] != ')' &&
+		!strings.HasPrefix(sc.rest(), "&&") && !strings.HasPrefix(sc.rest(), "||") {
+		sc.pos++
+	}
+	if sc.pos == start {
+		return "", fmt.Errorf("pathexpr: expected value at %d", start)
+	}
+	return sc.s[start:sc.pos], nil
+}
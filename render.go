@@ -0,0 +1,141 @@
+package main
+
+import (
+	stdjson "encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// renderNested renders a (possibly nested) field into one or more `key=val`
+// display lines. matchPath is the field's full dotted path, used to apply
+// -skip/-only and to pick a colorizeKeyVals color by its leaf name; label
+// is what's actually printed before '=' (the full matchPath when -flatten
+// is set, just the immediate key otherwise).
+func renderNested(matchPath, label string, val interface{}, keyCol func(a ...interface{}) string) []string {
+	switch v := val.(type) {
+	case map[string]interface{}:
+		return renderMap(matchPath, label, v, keyCol)
+	case []interface{}:
+		return renderSlice(matchPath, label, v, keyCol)
+	default:
+		return []string{renderScalarLine(matchPath, label, val, keyCol)}
+	}
+}
+
+// nestedKeys returns m's keys in display order, with empty/nil values and
+// -skip/-only exclusions already applied against their full dotted path
+// (parent + "." + key).
+func nestedKeys(parentPath string, m map[string]interface{}) []string {
+	var keys []string
+	for key := range m {
+		keys = append(keys, key)
+	}
+	keys = orderSortKeys(keys, keyOrder)
+
+	var out []string
+	for _, key := range keys {
+		val := m[key]
+		if val == "" || val == nil {
+			continue
+		}
+		full := parentPath + "." + key
+		if skip(full) {
+			continue
+		}
+		if len(showOnlyKeyList) > 0 && !showKey(full) {
+			continue
+		}
+		out = append(out, key)
+	}
+	return out
+}
+
+func renderMap(matchPath, label string, m map[string]interface{}, keyCol func(a ...interface{}) string) []string {
+	keys := nestedKeys(matchPath, m)
+
+	if flatten {
+		var lines []string
+		for _, key := range keys {
+			lines = append(lines, renderNested(matchPath+"."+key, label+"."+key, m[key], keyCol)...)
+		}
+		return lines
+	}
+
+	var parts []string
+	for _, key := range keys {
+		for _, line := range renderNested(matchPath+"."+key, key, m[key], keyCol) {
+			parts = append(parts, strings.TrimSuffix(line, "\n"))
+		}
+	}
+	return []string{fmt.Sprintf("%s={%s}\n", keyCol(label), strings.Join(parts, " "))}
+}
+
+func renderSlice(matchPath, label string, arr []interface{}, keyCol func(a ...interface{}) string) []string {
+	if len(arr) == 0 {
+		return nil
+	}
+
+	allScalar := true
+	for _, elem := range arr {
+		switch elem.(type) {
+		case map[string]interface{}, []interface{}:
+			allScalar = false
+		}
+	}
+	if allScalar {
+		vals := make([]string, len(arr))
+		for i, elem := range arr {
+			vals[i] = fmt.Sprintf("%v", formatScalar(elem))
+		}
+		return []string{fmt.Sprintf("%s=%s\n", keyCol(label), strings.Join(vals, ","))}
+	}
+
+	var lines []string
+	for i, elem := range arr {
+		elemMatch := fmt.Sprintf("%s[%d]", matchPath, i)
+		elemLabel := fmt.Sprintf("%s[%d]", label, i)
+		lines = append(lines, renderNested(elemMatch, elemLabel, elem, keyCol)...)
+	}
+	return lines
+}
+
+func renderScalarLine(matchPath, label string, val interface{}, keyCol func(a ...interface{}) string) string {
+	sval := formatScalar(val)
+	if col, ok := colorizeKeyVals[leafKey(matchPath)]; ok {
+		valColor := color.New(col).SprintFunc()
+		return fmt.Sprintf("%s=%v\n", keyCol(label), valColor(sval))
+	}
+	return fmt.Sprintf("%s=%v\n", keyCol(label), sval)
+}
+
+// formatScalar applies field-specific formatting: stdjson.Number is printed
+// without scientific notation, and time.Time (the output of
+// convertUnixTimestamp) honors -time-format.
+func formatScalar(val interface{}) interface{} {
+	switch v := val.(type) {
+	case stdjson.Number:
+		if f, err := v.Float64(); err == nil {
+			return strconv.FormatFloat(f, 'f', -1, 64)
+		}
+		return string(v)
+	case time.Time:
+		if timeFormat != "" {
+			return v.Format(timeFormat)
+		}
+		return v.String()
+	default:
+		return val
+	}
+}
+
+// leafKey returns the last dotted segment of path, e.g. "req.status" -> "status".
+func leafKey(path string) string {
+	if i := strings.LastIndex(path, "."); i >= 0 {
+		return path[i+1:]
+	}
+	return path
+}
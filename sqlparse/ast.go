@@ -0,0 +1,75 @@
+// Package sqlparse provides a small tokenizer and recursive-descent parser
+// for the subset of SQL that shows up in application logs: single-statement
+// INSERT, UPDATE, DELETE and SELECT queries, typically with `$1..$N`
+// placeholders already substituted with literal values. It is not a
+// general-purpose SQL parser; it extracts just enough structure (table
+// name, columns, SET/WHERE clauses) to build a human-readable summary.
+package sqlparse
+
+// Stmt is implemented by every parsed statement type recognised by
+// Analyze: InsertStmt, UpdateStmt, DeleteStmt and SelectStmt.
+type Stmt interface {
+	isStmt()
+}
+
+// Predicate represents a single `column <op> value` condition extracted
+// from a WHERE clause.
+type Predicate struct {
+	Column string
+	Op     string
+	Value  string
+}
+
+// Assignment represents a single `column = value` pair from an UPDATE
+// statement's SET clause.
+type Assignment struct {
+	Column string
+	Value  string
+}
+
+// InsertStmt represents a parsed
+// `INSERT INTO "table" (cols) VALUES (vals), (vals), ... [RETURNING "table"."col"]`
+// statement. Bulk inserts with multiple VALUES tuples produce one entry in
+// Rows per tuple.
+type InsertStmt struct {
+	// Table is the table being inserted into.
+	Table string
+	// Columns are the column names, in VALUES order.
+	Columns []string
+	// Rows holds one entry per VALUES tuple, each matching Columns in order.
+	Rows [][]string
+	// RetTable and RetColumn hold the RETURNING clause target, if present.
+	RetTable  string
+	RetColumn string
+}
+
+func (*InsertStmt) isStmt() {}
+
+// UpdateStmt represents a parsed `UPDATE "table" SET ... [WHERE ...]`
+// statement.
+type UpdateStmt struct {
+	Table string
+	Set   []Assignment
+	Where []Predicate
+}
+
+func (*UpdateStmt) isStmt() {}
+
+// DeleteStmt represents a parsed `DELETE FROM "table" [WHERE ...]`
+// statement.
+type DeleteStmt struct {
+	Table string
+	Where []Predicate
+}
+
+func (*DeleteStmt) isStmt() {}
+
+// SelectStmt represents a parsed `SELECT cols FROM "table" [WHERE ...]`
+// statement.
+type SelectStmt struct {
+	Columns []string
+	Table   string
+	Where   []Predicate
+}
+
+func (*SelectStmt) isStmt() {}
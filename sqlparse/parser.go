@@ -0,0 +1,360 @@
+package sqlparse
+
+import (
+	"fmt"
+)
+
+// Analyze tokenizes and parses a single SQL statement and returns its
+// parsed representation. Supported statements are INSERT, UPDATE, DELETE
+// and SELECT; anything else returns an error.
+func Analyze(query string) (Stmt, error) {
+	toks, err := tokenize(query)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{toks: toks}
+	switch p.peek().val {
+	case "INSERT":
+		return p.parseInsert()
+	case "UPDATE":
+		return p.parseUpdate()
+	case "DELETE":
+		return p.parseDelete()
+	case "SELECT":
+		return p.parseSelect()
+	default:
+		return nil, fmt.Errorf("sqlparse: unsupported statement %q", p.peek().val)
+	}
+}
+
+type parser struct {
+	toks []token
+	pos  int
+}
+
+func (p *parser) peek() token {
+	return p.toks[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.toks[p.pos]
+	if p.pos < len(p.toks)-1 {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) expectKeyword(kw string) error {
+	t := p.next()
+	if t.kind != tokKeyword || t.val != kw {
+		return fmt.Errorf("sqlparse: expected %s, got %q", kw, t.val)
+	}
+	return nil
+}
+
+func (p *parser) expectPunct(val string) error {
+	t := p.next()
+	if t.kind != tokPunct || t.val != val {
+		return fmt.Errorf("sqlparse: expected %q, got %q", val, t.val)
+	}
+	return nil
+}
+
+// tableName parses a (possibly schema-qualified) table name, e.g. "table"
+// or "schema"."table", and returns its last component.
+func (p *parser) tableName() (string, error) {
+	t := p.next()
+	if t.kind != tokQuotedIdent && t.kind != tokIdent {
+		return "", fmt.Errorf("sqlparse: expected table name, got %q", t.val)
+	}
+	name := t.val
+	for p.peek().kind == tokPunct && p.peek().val == "." {
+		p.next()
+		t := p.next()
+		if t.kind != tokQuotedIdent && t.kind != tokIdent {
+			return "", fmt.Errorf("sqlparse: expected identifier after '.', got %q", t.val)
+		}
+		name = t.val
+	}
+	return name, nil
+}
+
+// columnList parses a parenthesised, comma-separated list of column names.
+func (p *parser) columnList() ([]string, error) {
+	if err := p.expectPunct("("); err != nil {
+		return nil, err
+	}
+	var cols []string
+	for {
+		t := p.next()
+		if t.kind != tokQuotedIdent && t.kind != tokIdent {
+			return nil, fmt.Errorf("sqlparse: expected column name, got %q", t.val)
+		}
+		cols = append(cols, t.val)
+		if p.peek().val == "," {
+			p.next()
+			continue
+		}
+		break
+	}
+	if err := p.expectPunct(")"); err != nil {
+		return nil, err
+	}
+	return cols, nil
+}
+
+// valueList parses a parenthesised, comma-separated list of literal values.
+func (p *parser) valueList() ([]string, error) {
+	if err := p.expectPunct("("); err != nil {
+		return nil, err
+	}
+	var vals []string
+	for {
+		v, err := p.literal()
+		if err != nil {
+			return nil, err
+		}
+		vals = append(vals, v)
+		if p.peek().val == "," {
+			p.next()
+			continue
+		}
+		break
+	}
+	if err := p.expectPunct(")"); err != nil {
+		return nil, err
+	}
+	return vals, nil
+}
+
+// literal parses a single string/number/NULL/boolean literal.
+func (p *parser) literal() (string, error) {
+	t := p.next()
+	switch t.kind {
+	case tokString, tokNumber, tokNull, tokBool, tokIdent:
+		return t.val, nil
+	default:
+		return "", fmt.Errorf("sqlparse: expected literal value, got %q", t.val)
+	}
+}
+
+// qualifiedColumn parses a column reference, optionally qualified with a
+// table name (`table.column`), and returns just the column part.
+func (p *parser) qualifiedColumn() (string, error) {
+	t := p.next()
+	if t.kind != tokQuotedIdent && t.kind != tokIdent {
+		return "", fmt.Errorf("sqlparse: expected column name, got %q", t.val)
+	}
+	col := t.val
+	if p.peek().kind == tokPunct && p.peek().val == "." {
+		p.next()
+		t := p.next()
+		if t.kind != tokQuotedIdent && t.kind != tokIdent {
+			return "", fmt.Errorf("sqlparse: expected identifier after '.', got %q", t.val)
+		}
+		col = t.val
+	}
+	return col, nil
+}
+
+// qualifiedIdentPair parses a single identifier, optionally qualified with
+// a leading table name (`table.column`), and returns both parts (table is
+// "" when unqualified). Unlike qualifiedColumn, which discards the
+// qualifier, RETURNING needs to keep both: `RETURNING "t"."id"` should
+// yield table="t", column="id" rather than just "id".
+func (p *parser) qualifiedIdentPair() (string, string, error) {
+	t := p.next()
+	if t.kind != tokQuotedIdent && t.kind != tokIdent {
+		return "", "", fmt.Errorf("sqlparse: expected identifier, got %q", t.val)
+	}
+	first := t.val
+	if p.peek().kind == tokPunct && p.peek().val == "." {
+		p.next()
+		t2 := p.next()
+		if t2.kind != tokQuotedIdent && t2.kind != tokIdent {
+			return "", "", fmt.Errorf("sqlparse: expected identifier after '.', got %q", t2.val)
+		}
+		return first, t2.val, nil
+	}
+	return "", first, nil
+}
+
+// where parses an optional WHERE clause into a flat list of predicates,
+// joined by AND/OR (the join keyword itself isn't preserved; it's not
+// needed to build a log summary).
+func (p *parser) where() ([]Predicate, error) {
+	if p.peek().kind != tokKeyword || p.peek().val != "WHERE" {
+		return nil, nil
+	}
+	p.next()
+
+	var preds []Predicate
+	for {
+		col, err := p.qualifiedColumn()
+		if err != nil {
+			return nil, err
+		}
+		op := p.next()
+		if op.kind != tokPunct {
+			return nil, fmt.Errorf("sqlparse: expected comparison operator, got %q", op.val)
+		}
+		val, err := p.literal()
+		if err != nil {
+			return nil, err
+		}
+		preds = append(preds, Predicate{Column: col, Op: op.val, Value: val})
+
+		if p.peek().kind == tokKeyword && (p.peek().val == "AND" || p.peek().val == "OR") {
+			p.next()
+			continue
+		}
+		break
+	}
+	return preds, nil
+}
+
+func (p *parser) parseInsert() (Stmt, error) {
+	if err := p.expectKeyword("INSERT"); err != nil {
+		return nil, err
+	}
+	if err := p.expectKeyword("INTO"); err != nil {
+		return nil, err
+	}
+	table, err := p.tableName()
+	if err != nil {
+		return nil, err
+	}
+	cols, err := p.columnList()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expectKeyword("VALUES"); err != nil {
+		return nil, err
+	}
+	vals, err := p.valueList()
+	if err != nil {
+		return nil, err
+	}
+	rows := [][]string{vals}
+	for p.peek().kind == tokPunct && p.peek().val == "," {
+		p.next()
+		vals, err := p.valueList()
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, vals)
+	}
+
+	stmt := &InsertStmt{Table: table, Columns: cols, Rows: rows}
+
+	if p.peek().kind == tokKeyword && p.peek().val == "RETURNING" {
+		p.next()
+		retTable, retCol, err := p.qualifiedIdentPair()
+		if err != nil {
+			return nil, err
+		}
+		stmt.RetTable = retTable
+		stmt.RetColumn = retCol
+	}
+
+	return stmt, nil
+}
+
+func (p *parser) parseUpdate() (Stmt, error) {
+	if err := p.expectKeyword("UPDATE"); err != nil {
+		return nil, err
+	}
+	table, err := p.tableName()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expectKeyword("SET"); err != nil {
+		return nil, err
+	}
+
+	var set []Assignment
+	for {
+		col, err := p.qualifiedColumn()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expectPunct("="); err != nil {
+			return nil, err
+		}
+		val, err := p.literal()
+		if err != nil {
+			return nil, err
+		}
+		set = append(set, Assignment{Column: col, Value: val})
+		if p.peek().val == "," {
+			p.next()
+			continue
+		}
+		break
+	}
+
+	where, err := p.where()
+	if err != nil {
+		return nil, err
+	}
+
+	return &UpdateStmt{Table: table, Set: set, Where: where}, nil
+}
+
+func (p *parser) parseDelete() (Stmt, error) {
+	if err := p.expectKeyword("DELETE"); err != nil {
+		return nil, err
+	}
+	if err := p.expectKeyword("FROM"); err != nil {
+		return nil, err
+	}
+	table, err := p.tableName()
+	if err != nil {
+		return nil, err
+	}
+	where, err := p.where()
+	if err != nil {
+		return nil, err
+	}
+	return &DeleteStmt{Table: table, Where: where}, nil
+}
+
+func (p *parser) parseSelect() (Stmt, error) {
+	if err := p.expectKeyword("SELECT"); err != nil {
+		return nil, err
+	}
+
+	var cols []string
+	for {
+		if p.peek().kind == tokPunct && p.peek().val == "*" {
+			p.next()
+			cols = append(cols, "*")
+		} else {
+			col, err := p.qualifiedColumn()
+			if err != nil {
+				return nil, err
+			}
+			cols = append(cols, col)
+		}
+		if p.peek().val == "," {
+			p.next()
+			continue
+		}
+		break
+	}
+
+	if err := p.expectKeyword("FROM"); err != nil {
+		return nil, err
+	}
+	table, err := p.tableName()
+	if err != nil {
+		return nil, err
+	}
+	where, err := p.where()
+	if err != nil {
+		return nil, err
+	}
+
+	return &SelectStmt{Columns: cols, Table: table, Where: where}, nil
+}
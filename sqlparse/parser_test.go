@@ -0,0 +1,103 @@
+package sqlparse
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAnalyze(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  Stmt
+	}{
+		{
+			name:  "insert",
+			query: `INSERT INTO "t" ("a","b") VALUES (1,2)`,
+			want: &InsertStmt{
+				Table:   "t",
+				Columns: []string{"a", "b"},
+				Rows:    [][]string{{"1", "2"}},
+			},
+		},
+		{
+			name:  "insert with returning",
+			query: `INSERT INTO "t" ("a") VALUES (1) RETURNING "t"."id"`,
+			want: &InsertStmt{
+				Table:     "t",
+				Columns:   []string{"a"},
+				Rows:      [][]string{{"1"}},
+				RetTable:  "t",
+				RetColumn: "id",
+			},
+		},
+		{
+			name:  "insert with unqualified returning",
+			query: `INSERT INTO "t" ("a") VALUES (1) RETURNING "id"`,
+			want: &InsertStmt{
+				Table:     "t",
+				Columns:   []string{"a"},
+				Rows:      [][]string{{"1"}},
+				RetColumn: "id",
+			},
+		},
+		{
+			name:  "bulk insert with returning",
+			query: `INSERT INTO "t" ("a") VALUES (1),(2) RETURNING "t"."id"`,
+			want: &InsertStmt{
+				Table:     "t",
+				Columns:   []string{"a"},
+				Rows:      [][]string{{"1"}, {"2"}},
+				RetTable:  "t",
+				RetColumn: "id",
+			},
+		},
+		{
+			name:  "update",
+			query: `UPDATE "t" SET "a" = 1, "b" = 2 WHERE "id" = 3`,
+			want: &UpdateStmt{
+				Table: "t",
+				Set: []Assignment{
+					{Column: "a", Value: "1"},
+					{Column: "b", Value: "2"},
+				},
+				Where: []Predicate{{Column: "id", Op: "=", Value: "3"}},
+			},
+		},
+		{
+			name:  "delete",
+			query: `DELETE FROM "t" WHERE "id" = 1`,
+			want: &DeleteStmt{
+				Table: "t",
+				Where: []Predicate{{Column: "id", Op: "=", Value: "1"}},
+			},
+		},
+		{
+			name:  "select",
+			query: `SELECT "a", "b" FROM "t" WHERE "id" = 1`,
+			want: &SelectStmt{
+				Columns: []string{"a", "b"},
+				Table:   "t",
+				Where:   []Predicate{{Column: "id", Op: "=", Value: "1"}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Analyze(tt.query)
+			if err != nil {
+				t.Fatalf("Analyze(%q) returned error: %v", tt.query, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Analyze(%q) = %#v, want %#v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAnalyzeUnsupported(t *testing.T) {
+	if _, err := Analyze(`DROP TABLE "t"`); err == nil {
+		t.Fatal("Analyze(DROP TABLE) = nil error, want error")
+	}
+}
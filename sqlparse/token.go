@@ -0,0 +1,167 @@
+package sqlparse
+
+import (
+	"fmt"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokKeyword
+	tokIdent
+	tokQuotedIdent
+	tokString
+	tokNumber
+	tokNull
+	tokBool
+	tokPunct
+)
+
+type token struct {
+	kind tokenKind
+	val  string
+}
+
+// keywords recognised by the tokenizer. Everything else that looks like an
+// identifier is returned as tokIdent.
+var keywords = map[string]bool{
+	"INSERT":    true,
+	"INTO":      true,
+	"VALUES":    true,
+	"RETURNING": true,
+	"UPDATE":    true,
+	"SET":       true,
+	"WHERE":     true,
+	"DELETE":    true,
+	"FROM":      true,
+	"SELECT":    true,
+	"AND":       true,
+	"OR":        true,
+}
+
+// tokenize turns a SQL statement into a flat list of tokens. It understands
+// double-quoted identifiers, single-quoted string literals (with `”` as an
+// escaped quote), numeric/NULL/boolean literals and nested parentheses.
+func tokenize(sql string) ([]token, error) {
+	var toks []token
+	runes := []rune(sql)
+	i := 0
+	n := len(runes)
+
+	for i < n {
+		c := runes[i]
+
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+
+		case c == '"':
+			start := i + 1
+			j := start
+			for j < n && runes[j] != '"' {
+				j++
+			}
+			if j >= n {
+				return nil, fmt.Errorf("sqlparse: unterminated quoted identifier at %d", start)
+			}
+			toks = append(toks, token{kind: tokQuotedIdent, val: string(runes[start:j])})
+			i = j + 1
+
+		case c == '\'':
+			var sb strings.Builder
+			j := i + 1
+			for {
+				if j >= n {
+					return nil, fmt.Errorf("sqlparse: unterminated string literal at %d", i)
+				}
+				if runes[j] == '\'' {
+					if j+1 < n && runes[j+1] == '\'' {
+						sb.WriteRune('\'')
+						j += 2
+						continue
+					}
+					break
+				}
+				sb.WriteRune(runes[j])
+				j++
+			}
+			toks = append(toks, token{kind: tokString, val: sb.String()})
+			i = j + 1
+
+		case c == '(' || c == ')' || c == ',' || c == '.' || c == ';' || c == '*':
+			toks = append(toks, token{kind: tokPunct, val: string(c)})
+			i++
+
+		case c == '=' || c == '!' || c == '<' || c == '>' || c == '~':
+			j := i + 1
+			if j < n && (runes[j] == '=' || (c == '<' && runes[j] == '>')) {
+				j++
+			}
+			toks = append(toks, token{kind: tokPunct, val: string(runes[i:j])})
+			i = j
+
+		case isDigit(c) || (c == '-' && i+1 < n && isDigit(runes[i+1])):
+			j := i + 1
+			for j < n && (isDigit(runes[j]) || runes[j] == '.') {
+				j++
+			}
+			toks = append(toks, token{kind: tokNumber, val: string(runes[i:j])})
+			i = j
+
+		case isIdentStart(c):
+			j := i + 1
+			for j < n && isIdentPart(runes[j]) {
+				j++
+			}
+			word := string(runes[i:j])
+			toks = append(toks, identToken(word))
+			i = j
+
+		case c == '$':
+			// leftover, unsubstituted placeholder ($1, $2, ...): treat as an
+			// opaque identifier so callers can surface the raw query.
+			j := i + 1
+			for j < n && isDigit(runes[j]) {
+				j++
+			}
+			toks = append(toks, token{kind: tokIdent, val: string(runes[i:j])})
+			i = j
+
+		default:
+			return nil, fmt.Errorf("sqlparse: unexpected character %q at %d", c, i)
+		}
+	}
+
+	toks = append(toks, token{kind: tokEOF})
+	return toks, nil
+}
+
+// identToken classifies a bare word as a keyword, NULL/boolean literal, or
+// plain identifier.
+func identToken(word string) token {
+	upper := strings.ToUpper(word)
+	switch upper {
+	case "NULL":
+		return token{kind: tokNull, val: word}
+	case "TRUE", "FALSE":
+		return token{kind: tokBool, val: word}
+	}
+	if keywords[upper] {
+		return token{kind: tokKeyword, val: upper}
+	}
+	return token{kind: tokIdent, val: word}
+}
+
+func isDigit(c rune) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentStart(c rune) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isIdentPart(c rune) bool {
+	return isIdentStart(c) || isDigit(c)
+}
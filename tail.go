@@ -0,0 +1,226 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// pollInterval is how often the polling fallback re-stats followed files
+// when fsnotify isn't available.
+const pollInterval = 200 * time.Millisecond
+
+// tailedLine is a single decoded line read by tailFiles, tagged with the
+// filename it came from (empty when only one file is followed).
+type tailedLine struct {
+	tag  string
+	data []byte
+}
+
+// tailFiles opens and follows the given file paths, emitting each line as
+// it's appended. It detects truncation (the file shrinks below the current
+// read offset) by seeking back to the start, and rotation (the path now
+// refers to a different inode) by reopening it. It prefers fsnotify for
+// event-driven tailing and falls back to polling when a watcher can't be
+// created.
+func tailFiles(paths []string) <-chan tailedLine {
+	out := make(chan tailedLine, 64)
+	multi := len(paths) > 1
+
+	go func() {
+		defer close(out)
+		watcher, err := fsnotify.NewWatcher()
+		if err != nil {
+			pollFiles(paths, multi, out)
+			return
+		}
+		defer watcher.Close()
+		watchFiles(watcher, paths, multi, out)
+	}()
+
+	return out
+}
+
+// tailState tracks one followed file's open handle and read position.
+type tailState struct {
+	path string
+	file *os.File
+	r    *bufio.Reader
+	ino  uint64
+}
+
+func openTail(path string) (*tailState, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	ino, _ := fileIno(fi)
+	return &tailState{path: path, file: f, r: bufio.NewReader(f), ino: ino}, nil
+}
+
+func fileTag(path string, multi bool) string {
+	if !multi {
+		return ""
+	}
+	return filepath.Base(path)
+}
+
+// drain reads and emits every complete line currently available, stopping
+// at EOF (a trailing partial line, if any, is left buffered for next time).
+func (t *tailState) drain(out chan<- tailedLine, multi bool) {
+	for {
+		line, err := t.r.ReadBytes('\n')
+		if len(line) > 0 {
+			line = bytes.TrimRight(line, "\n")
+			if len(line) > 0 {
+				out <- tailedLine{tag: fileTag(t.path, multi), data: append([]byte(nil), line...)}
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// checkRotation reports whether the path now points at a different inode
+// than the one we have open (log rotation), and handles truncation (the
+// file shrank below our read offset) by seeking back to the start.
+func (t *tailState) checkRotation() bool {
+	fi, err := os.Stat(t.path)
+	if err != nil {
+		return false
+	}
+	if ino, ok := fileIno(fi); ok && ino != t.ino {
+		return true
+	}
+	pos, err := t.file.Seek(0, io.SeekCurrent)
+	if err == nil && fi.Size() < pos {
+		t.file.Seek(0, io.SeekStart)
+		t.r.Reset(t.file)
+	}
+	return false
+}
+
+// reopen closes the current handle and opens the path anew, used after
+// rotation replaces the file at t.path with a new one.
+func (t *tailState) reopen() error {
+	f, err := os.Open(t.path)
+	if err != nil {
+		return err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	t.file.Close()
+	t.file = f
+	t.r = bufio.NewReader(f)
+	t.ino, _ = fileIno(fi)
+	return nil
+}
+
+// pollFiles is the fallback tailing strategy used when no fsnotify watcher
+// could be created: stat and re-read each file on a fixed interval.
+func pollFiles(paths []string, multi bool, out chan<- tailedLine) {
+	var states []*tailState
+	for _, p := range paths {
+		st, err := openTail(p)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "follow: cannot open %s: %v\n", p, err)
+			continue
+		}
+		st.drain(out, multi)
+		states = append(states, st)
+	}
+
+	for {
+		time.Sleep(pollInterval)
+		for _, st := range states {
+			if st.checkRotation() {
+				if err := st.reopen(); err != nil {
+					fmt.Fprintf(os.Stderr, "follow: cannot reopen %s: %v\n", st.path, err)
+					continue
+				}
+			}
+			st.drain(out, multi)
+		}
+	}
+}
+
+// watchFiles is the fsnotify-backed tailing strategy: it watches each
+// followed file for writes/removal and its containing directory for the
+// file reappearing (the common rotation pattern: rename old, create new).
+func watchFiles(watcher *fsnotify.Watcher, paths []string, multi bool, out chan<- tailedLine) {
+	states := make(map[string]*tailState, len(paths))
+	watchedDirs := make(map[string]bool)
+
+	for _, p := range paths {
+		st, err := openTail(p)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "follow: cannot open %s: %v\n", p, err)
+			continue
+		}
+		st.drain(out, multi)
+		states[p] = st
+
+		if err := watcher.Add(p); err != nil {
+			fmt.Fprintf(os.Stderr, "follow: cannot watch %s: %v\n", p, err)
+		}
+		dir := filepath.Dir(p)
+		if !watchedDirs[dir] {
+			if err := watcher.Add(dir); err == nil {
+				watchedDirs[dir] = true
+			}
+		}
+	}
+	if len(states) == 0 {
+		return
+	}
+
+	for {
+		select {
+		case ev, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			st, tracked := states[ev.Name]
+			if !tracked {
+				continue
+			}
+			switch {
+			case ev.Op&(fsnotify.Remove|fsnotify.Rename) != 0:
+				// the file is gone from under us; wait for a Create event
+				// on the directory to pick up the new file at this path.
+			case ev.Op&fsnotify.Write != 0:
+				if st.checkRotation() {
+					if err := st.reopen(); err == nil {
+						watcher.Add(ev.Name)
+					}
+				}
+				st.drain(out, multi)
+			case ev.Op&fsnotify.Create != 0:
+				if err := st.reopen(); err == nil {
+					watcher.Add(ev.Name)
+					st.drain(out, multi)
+				}
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			fmt.Fprintf(os.Stderr, "follow: watcher error: %v\n", err)
+		}
+	}
+}
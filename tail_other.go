@@ -0,0 +1,11 @@
+//go:build !unix
+
+package main
+
+import "os"
+
+// fileIno reports no inode on platforms without one (e.g. Windows), so
+// rotation detection falls back to size/truncation checks alone.
+func fileIno(fi os.FileInfo) (uint64, bool) {
+	return 0, false
+}